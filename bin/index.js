@@ -13,7 +13,10 @@
 const zxcvbn = require("zxcvbn")
 const { pwnedPassword } = require("hibp")
 const fs = require("fs")
+const os = require("os")
+const child_process = require("child_process")
 const crypto = require("../lib/crypto.js")
+const atomic = require("../lib/atomic.js")
 const read = require("better_read")
 const chalk = require("chalk")
 const clipboardy = require("clipboardy")
@@ -22,11 +25,32 @@ const e = require("../lib/escapes.js")
 
 /*
  * Terminal text themes
+ *
+ * Styling follows the --color=auto|always|never
+ * flag. In auto mode it is disabled if the
+ * NO_COLOR environment variable is set.
+ * (https://no-color.org)
  */
 
+const COLOR_ARG = /^--(no-)?color(=|$)/,
+  COLOR_FLAGS = [
+    "--color",
+    "--no-color",
+    "--color=auto",
+    "--color=always",
+    "--color=never",
+  ],
+  COLOR = process.argv.filter(arg => COLOR_ARG.test(arg)).pop()
+
+if (["--no-color", "--color=never"].includes(COLOR)) chalk.level = 0
+else if (["--color", "--color=always"].includes(COLOR))
+  chalk.level = chalk.level || 1
+else if (process.env.NO_COLOR) chalk.level = 0
+
 const WARN = chalk.red.bold,
   CODE = chalk.black.bgGreen,
-  OK = chalk.green.bold
+  OK = chalk.green.bold,
+  RESET = chalk.level ? e.GRAPHIC_MODE.RESET : ""
 
 /*
  * Constants
@@ -71,6 +95,19 @@ const _DATA_TEMPLATE = {
     "notes",
   ],
   _BASENAME = /[A-Za-z0-9-_.,]{1,100}/,
+  _STRENGTHS = [
+    WARN("[VERY WEAK]"),
+    chalk.yellow.bold("[WEAK]"),
+    "[MEDIUM]",
+    chalk.blue.bold("[STRONG]"),
+    OK("[VERY STRONG]"),
+  ],
+  _MIN_STRENGTH = 2,
+  _SESSION_TTL = 10,
+  _MAX_ATTEMPTS = 5,
+  _ATTEMPT_WINDOW = 15 * 60000,
+  _LOCKOUT_TIME = 15 * 60000,
+  _MAX_TIMEOUT = 2147483647,
   _HELP = {
     krypt: {
       use: "Global Krypt command.",
@@ -81,6 +118,24 @@ const _DATA_TEMPLATE = {
           alias: "-fs",
           value: "void",
         },
+        force_weak_password: {
+          use:
+            "Accept a master password below the minimum strength when initializing a database.",
+          alias: "-fwp",
+          value: "void",
+        },
+        session_ttl: {
+          use:
+            "Lock the session after the given minutes of inactivity.\n    Defaults to 10, 0 disables locking.",
+          alias: "-st",
+          value: "Integer",
+        },
+        color: {
+          use:
+            "Set when to use colored output, as --color=<value>.\n    Works with every krypt command.\n    Values:\n    auto (default, honors NO_COLOR)\n    always\n    never (same as --no-color)",
+          alias: "none",
+          value: "String",
+        },
       },
       new: {
         format: "krypt new",
@@ -133,6 +188,13 @@ const _DATA_TEMPLATE = {
     change: {
       format: "change",
       use: "Change the master password.",
+      flags: {
+        force_weak_password: {
+          use: "Accept a master password below the minimum strength.",
+          alias: "-fwp",
+          value: "void",
+        },
+      },
     },
     exit: {
       format: "exit",
@@ -251,6 +313,18 @@ const _DATA_TEMPLATE = {
             alias: "-u",
             value: "String",
           },
+          clear_after: {
+            use:
+              "Clear the clipboard after the given number of seconds.\n    Defaults to 45, 0 disables clearing, maximum 2147483.",
+            alias: "-cla",
+            value: "Integer",
+          },
+          field: {
+            use:
+              "Copy the given field of the password.\n    Values:\n    password (default)\n    username",
+            alias: "-f",
+            value: "String",
+          },
         },
       },
     },
@@ -352,7 +426,8 @@ let _DATABASE,
   _WORDS,
   _TREE,
   _MAST,
-  _NOTES
+  _NOTES,
+  _CLIP
 
 /*
  * Main function
@@ -361,7 +436,7 @@ let _DATABASE,
  * of Krypt. It is invoked if Krypt receives no args.
  */
 
-async function main() {
+async function main(weakPassword, sessionTTL) {
   /*
    * Main process local functions
    */
@@ -401,6 +476,47 @@ async function main() {
     console.log(OK("Logged in."))
   }
 
+  async function authenticate() {
+    const until = lockedUntil()
+    if (until) {
+      console.log(
+        WARN(
+          `Too many failed attempts. Database locked until ${new Date(
+            until
+          ).toLocaleString()}.`
+        )
+      )
+      return false
+    }
+    if (await login()) {
+      clearFailures()
+      return true
+    }
+    recordFailure()
+    loginFailed()
+    return false
+  }
+
+  function loginFailed() {
+    console.log(
+      WARN(
+        _DATABASE.settings.TwoFA.on
+          ? "Wrong Password or 2nd factor."
+          : "Wrong Password."
+      )
+    )
+    if (_DATABASE.settings.hint.on)
+      console.log(OK(`Hint: ${_DATABASE.settings.hint.hint}`))
+  }
+
+  function lock() {
+    _PASSWORDS = _NOTES = _KEY = _2F = _MAST = undefined
+    if (_CLIP) clearClipboard()
+    locked = true
+    console.log(e.ERASE.CLEAR_SCREEN + e.CURSOR.HOME)
+    log(WARN("Session locked due to inactivity. Press enter to log in."))
+  }
+
   async function parseInput() {
     function suggest(line) {
       const aLine = line.split(" ")
@@ -440,14 +556,30 @@ async function main() {
     return input.filter(item => item !== "")
   }
 
+  let locked = false
+
   if (fs.existsSync(__dirname + "/../databases/" + _NAME + ".json")) {
     if (!loadDatabase()) return
-    if (await login()) {
+    if (weakPassword)
+      console.log(
+        WARN("--force-weak-password only applies when initializing a database.")
+      )
+    if (await authenticate()) {
       hideLogin()
       loadData()
       main: while (true) {
+        let timer
+        if (sessionTTL) timer = setTimeout(lock, sessionTTL * 60000)
         let input = await parseInput()
+        clearTimeout(timer)
         console.log()
+        if (locked) {
+          if (!(await authenticate())) break main
+          hideLogin()
+          loadData()
+          locked = false
+          continue main
+        }
         if (input[0] === "exit") {
           if (input.length > 2) {
             console.log(
@@ -463,11 +595,19 @@ async function main() {
           console.log(e.ERASE.CLEAR_SCREEN)
           break
         } else if (input[0] === "change") {
-          if (input.length > 1) {
-            console.log(WARN(`Expected 0 arg(s), received ${input.length - 1}`))
+          if (input.length > 2) {
+            console.log(
+              WARN(`Expected 0-1 arg(s), received ${input.length - 1}`)
+            )
+            continue main
+          }
+          let weak = false
+          if (["--force-weak-password", "-fwp"].includes(input[1])) weak = true
+          else if (input.length > 1) {
+            console.log(WARN(`Invalid argument.`))
             continue main
           }
-          _KEY = crypto.PBKDF2_HASH(await newPassword())
+          _KEY = crypto.PBKDF2_HASH(await newPassword(weak))
           _DATABASE.salt.key = _KEY.salt
           _KEY = _KEY.checksum
           _DATABASE.checksum = crypto.PBKDF2_HASH(_KEY)
@@ -648,22 +788,59 @@ async function main() {
             console.log(OK("Successfully edited password."))
             reEncryptData()
           } else if (input[0] === "copy") {
-            if (input.length < 2) {
-              console.log(
-                WARN(`Expected multiple arg(s), received ${input.length - 1}`)
-              )
+            let matches,
+              clearAfter = 45,
+              field = "password"
+            input = input.slice(1)
+            for (const flag of ["--clear-after", "-cla"]) {
+              if (input.includes(flag)) {
+                clearAfter = parseInt(input[input.indexOf(flag) + 1])
+                input.splice(input.indexOf(flag), 2)
+              }
+            }
+            for (const flag of ["--field", "-f"]) {
+              if (input.includes(flag)) {
+                field = input[input.indexOf(flag) + 1]
+                input.splice(input.indexOf(flag), 2)
+              }
+            }
+            if (input.length === 0) {
+              console.log(WARN(`Expected multiple arg(s), received 0`))
+              continue main
+            }
+            if (
+              Number.isNaN(clearAfter) ||
+              clearAfter < 0 ||
+              clearAfter * 1000 > _MAX_TIMEOUT
+            ) {
+              console.log(WARN("Invalid clear time."))
+              continue main
+            }
+            if (!["password", "username"].includes(field)) {
+              console.log(WARN("Invalid field."))
               continue main
             }
-            let matches
             try {
-              matches = await filterPass(input.slice(1))
+              matches = await filterPass(input)
             } catch (e) {
               console.log(e.message)
               continue main
             }
             if (matches.length) {
-              clipboardy.writeSync(_PASSWORDS[matches[0]].password)
-              console.log(OK("Password copied to clipboard."))
+              const value = _PASSWORDS[matches[0]][field]
+              if (!copyPass(value, clearAfter)) {
+                console.log(
+                  WARN(`Clipboard unavailable, printing the ${field} instead.`)
+                )
+                console.log(value)
+                continue main
+              }
+              const label = field[0].toUpperCase() + field.slice(1)
+              console.log(OK(`${label} copied to clipboard.`))
+              if (clearAfter)
+                console.log(
+                  OK(`Clipboard will be cleared in ${clearAfter} seconds.`)
+                )
             } else {
               console.log(WARN("No matches found."))
             }
@@ -1036,7 +1213,7 @@ async function main() {
           if (!fs.existsSync(__dirname + "/../databases/" + _NAME))
             fs.mkdirSync(__dirname + "/../databases/" + _NAME)
           if (!fs.existsSync(__dirname + "/../databases/" + _NAME + "/.tree"))
-            fs.writeFileSync(
+            atomic.writeFileSync(
               __dirname + "/../databases/" + _NAME + "/.tree",
               "{}"
             )
@@ -1060,7 +1237,7 @@ async function main() {
               const fName = await read.prompt("Enter file name: ")
               if (_TREE[fName] === undefined) {
                 _TREE[fName] = fPath
-                fs.writeFileSync(
+                atomic.writeFileSync(
                   __dirname + "/../databases/" + _NAME + "/" + fName + ".karc",
                   JSON.stringify(binEncryptFile(fs.readFileSync(fPath)))
                 )
@@ -1086,7 +1263,7 @@ async function main() {
                   num = 0
                 let files = getAllFiles(fPath).forEach(item => {
                   dirTree[num.toString()] = item
-                  fs.writeFileSync(
+                  atomic.writeFileSync(
                     __dirname +
                       "/../databases/" +
                       _NAME +
@@ -1102,7 +1279,7 @@ async function main() {
                   fs.unlinkSync(fPath + "/" + item)
                   num++
                 })
-                fs.writeFileSync(
+                atomic.writeFileSync(
                   __dirname + "/../databases/" + _NAME + "/" + fName + "/.tree",
                   JSON.stringify(dirTree)
                 )
@@ -1222,7 +1399,7 @@ async function main() {
               input = input.replace(/\\n/g, "\n")
               console.log("\n")
               printNote({ name: name, info: input, date: new Date() }, 0)
-              log(e.GRAPHIC_MODE.RESET)
+              log(RESET)
             })
             let note = await read.prompt("Enter your note:")
             note = note.replace(/\\n/g, "\n")
@@ -1283,16 +1460,7 @@ async function main() {
           console.log(WARN("Invalid command."))
         }
       }
-    } else {
-      console.log(
-        WARN(
-          _DATABASE.settings.TwoFA.on
-            ? "Wrong Password or 2nd factor."
-            : "Wrong Password."
-        )
-      )
-      if (_DATABASE.settings.hint.on)
-        console.log(OK(`Hint: ${_DATABASE.settings.hint.hint}`))
+      if (_CLIP) deferClipboard()
     }
   } else {
     if (!fs.existsSync(__dirname + "/../databases"))
@@ -1304,7 +1472,7 @@ async function main() {
     _DATABASE = _DATA_TEMPLATE
     _PASSWORDS = []
     _NOTES = []
-    _KEY = crypto.PBKDF2_HASH(await newPassword())
+    _KEY = crypto.PBKDF2_HASH(await newPassword(weakPassword))
     _DATABASE.salt.key = _KEY.salt
     _KEY = _KEY.checksum
     _DATABASE.checksum = crypto.PBKDF2_HASH(_KEY)
@@ -1409,6 +1577,35 @@ async function main() {
  *        note -> Object > note
  *        index -> Number
  *      Prints out the arg:note with formatting.
+ * [24] copyPass
+ *        pass -> String
+ *        seconds -> Number
+ *      Copies arg:pass to the clipboard, clearing it after arg:seconds.
+ *      returns -> Boolean
+ * [25] clearClipboard
+ *      Clears the clipboard if it still holds the copied password.
+ *      returns -> void
+ * [26] deferClipboard
+ *      Leaves the pending clipboard clear to a detached process.
+ *      returns -> void
+ * [27] loadAttempts
+ *      Loads the failed login attempts of the selected database
+ *      returns -> Object > attempts
+ * [28] recordFailure
+ *      Records a failed login, locking the database after too many
+ *      returns -> void
+ * [29] clearFailures
+ *      Forgets the failed login attempts of the selected database
+ *      returns -> void
+ * [30] lockedUntil
+ *      Gives the time the selected database is locked until
+ *      returns -> Number > timestamp | 0
+ * [31] getConfig
+ *        key -> String
+ *        fallback -> Any
+ *        valid -> Function
+ *      Gives arg:key of config.json if arg:valid accepts it, else arg:fallback
+ *      returns -> Any
  */
 
 function isNotCommand(name) {
@@ -1474,17 +1671,9 @@ function generatePassword(wordy) {
 }
 
 function passStrength(passwordS) {
-  const measure = [
-    WARN("[VERY WEAK]"),
-    chalk.yellow.bold("[WEAK]"),
-    "[MEDIUM]",
-    chalk.blue.bold("[STRONG]"),
-    OK("[VERY STRONG]"),
-  ]
-
   const power = zxcvbn(passwordS)
   return {
-    score: measure[power.score],
+    score: _STRENGTHS[power.score],
     time: power.crack_times_display.online_no_throttling_10_per_second,
     feedback: power.feedback,
   }
@@ -1547,7 +1736,7 @@ function reEncryptData() {
     )
     _DATABASE.data.notes = crypto.AES_encrypt(JSON.stringify(_NOTES), _KEY)
   }
-  fs.writeFileSync(
+  atomic.writeFileSync(
     __dirname + "/../databases/" + _NAME + ".json",
     JSON.stringify(_DATABASE)
   )
@@ -1592,7 +1781,7 @@ function binDecryptFile(ciphertext) {
 }
 
 function updateTree() {
-  fs.writeFileSync(
+  atomic.writeFileSync(
     __dirname + "/../databases/" + _NAME + "/.tree",
     JSON.stringify(_TREE)
   )
@@ -1670,24 +1859,126 @@ function printNote(note, index) {
   while (_FORMAT_OP.exec(str)) {
     let token = _FORMAT_OP.exec(str)[0]
     token = token.substring(1, token.length - 1)
-    str = str.replace(_FORMAT_OP, style[token].open)
+    str = str.replace(_FORMAT_OP, chalk.level ? style[token].open : "")
   }
 
   while (_FORMAT_CL.exec(str)) {
     let token = _FORMAT_CL.exec(str)[0]
     token = token.substring(2, token.length - 1)
-    str = str.replace(_FORMAT_CL, style[token].close)
+    str = str.replace(_FORMAT_CL, chalk.level ? style[token].close : "")
   }
 
   console.log(
     `${chalk.bold(`[${index}] ${note.name}`)}\n${chalk.bold(
       note.date
-    )}\n\n${"-".repeat(24)}\n\n${str}\n${e.GRAPHIC_MODE.RESET}\n${"-".repeat(
-      24
-    )}`
+    )}\n\n${"-".repeat(24)}\n\n${str}\n${RESET}\n${"-".repeat(24)}`
   )
 }
 
+function copyPass(pass, seconds) {
+  if (_CLIP) clearTimeout(_CLIP.timer)
+  _CLIP = undefined
+  try {
+    clipboardy.writeSync(pass)
+  } catch {
+    return false
+  }
+  if (seconds)
+    _CLIP = {
+      pass: pass,
+      at: Date.now() + seconds * 1000,
+      timer: setTimeout(clearClipboard, seconds * 1000),
+    }
+  return true
+}
+
+function clearClipboard() {
+  clearTimeout(_CLIP.timer)
+  try {
+    if (clipboardy.readSync() === _CLIP.pass) clipboardy.writeSync("")
+  } catch {}
+  _CLIP = undefined
+}
+
+function deferClipboard() {
+  clearTimeout(_CLIP.timer)
+  try {
+    // The password is piped in so it never shows up in the process list.
+    const clearer = child_process.spawn(
+      process.execPath,
+      [__dirname + "/../lib/clear.js", String(_CLIP.at - Date.now())],
+      { detached: true, stdio: ["pipe", "ignore", "ignore"] }
+    )
+    clearer.stdin.end(_CLIP.pass)
+    clearer.unref()
+    _CLIP = undefined
+  } catch {
+    clearClipboard()
+  }
+}
+
+function loadAttempts() {
+  let attempts
+  try {
+    attempts = JSON.parse(
+      fs.readFileSync(__dirname + "/../databases/" + _NAME + ".lock")
+    )
+  } catch {}
+  if (
+    !attempts ||
+    !Array.isArray(attempts.failures) ||
+    typeof attempts.until !== "number"
+  )
+    return { failures: [], until: 0 }
+  attempts.failures = attempts.failures.filter(time => typeof time === "number")
+  return attempts
+}
+
+function recordFailure() {
+  const attempts = loadAttempts(),
+    now = Date.now()
+  attempts.failures = attempts.failures.filter(
+    time => now - time < _ATTEMPT_WINDOW
+  )
+  attempts.failures.push(now)
+  const maxAttempts = getConfig(
+      "maxAttempts",
+      _MAX_ATTEMPTS,
+      value => Number.isInteger(value) && value > 0
+    ),
+    lockoutTime = getConfig(
+      "lockoutTime",
+      _LOCKOUT_TIME / 60000,
+      value => typeof value === "number" && value > 0
+    )
+  if (attempts.failures.length >= maxAttempts) {
+    attempts.failures = []
+    attempts.until = now + lockoutTime * 60000
+  }
+  atomic.writeFileSync(
+    __dirname + "/../databases/" + _NAME + ".lock",
+    JSON.stringify(attempts)
+  )
+}
+
+function clearFailures() {
+  if (fs.existsSync(__dirname + "/../databases/" + _NAME + ".lock"))
+    fs.unlinkSync(__dirname + "/../databases/" + _NAME + ".lock")
+}
+
+function lockedUntil() {
+  const until = loadAttempts().until
+  return until > Date.now() ? until : 0
+}
+
+function getConfig(key, fallback, valid) {
+  const config = fs.existsSync(__dirname + "/../config.json") && getDatabases()
+  if (!config || !(key in config)) return fallback
+  if (valid(config[key])) return config[key]
+  console.log(WARN(`Invalid ${key} in config.json, using ${fallback}.`))
+  return fallback
+}
+
 function parseAlias(name, command) {
   let comms = [],
     store = "",
@@ -1785,15 +2076,8 @@ async function filterPass(filters) {
         case "--strength":
         case "-s":
           const strength = passStrength(_PASSWORDS[i].password).score,
-            measure = [
-              WARN("[VERY WEAK]"),
-              chalk.yellow.bold("[WEAK]"),
-              "[MEDIUM]",
-              chalk.blue.bold("[STRONG]"),
-              OK("[VERY STRONG]"),
-            ],
             keywords = ["very-weak", "weak", "medium", "strong", "very-strong"],
-            index = measure.indexOf(strength)
+            index = _STRENGTHS.indexOf(strength)
           if ("01234".includes(filters[j + 1])) {
             if (index.toString() === filters[j + 1]) prev = true
           } else if (keywords.includes(filters[j + 1])) {
@@ -1816,9 +2100,27 @@ function log(query) {
   process.stdout.write(query)
 }
 
-async function newPassword() {
+async function newPassword(weak) {
+  const minStrength = getConfig(
+    "minStrength",
+    _MIN_STRENGTH,
+    value => Number.isInteger(value) && value >= 0 && value <= 4
+  )
   pass: while (true) {
     let password = await read.prompt("Enter new password: ", true)
+    const power = zxcvbn(password)
+    if (!weak && power.score < minStrength) {
+      console.log(
+        WARN("Password is too weak. Use at least a ") +
+          _STRENGTHS[minStrength] +
+          WARN(" strength password.")
+      )
+      if (power.feedback.warning)
+        console.log(WARN(`Warning: ${power.feedback.warning}`))
+      if (power.feedback.suggestions.length !== 0)
+        console.log(OK(`Suggestions: ${power.feedback.suggestions.join(", ")}`))
+      continue pass
+    }
     if ((await read.prompt("Re-enter the password: ", true)) === password)
       return password
     else {
@@ -1846,11 +2148,48 @@ async function newPassword() {
 
 ;(async function () {
   let args = process.argv.slice(2)
+  if (args.some(arg => COLOR_ARG.test(arg) && !COLOR_FLAGS.includes(arg))) {
+    console.log(WARN("Invalid argument."))
+    return
+  }
+  args = args.filter(arg => !COLOR_ARG.test(arg))
+  process.on("exit", () => {
+    if (_CLIP) clearClipboard()
+  })
+  for (const signal of ["SIGINT", "SIGTERM", "SIGHUP"])
+    process.on(signal, () => process.exit(128 + os.constants.signals[signal]))
   _WORDS = JSON.parse(fs.readFileSync(__dirname + "/../lib/dictionary.json"))
-  if (args.length === 0 || ["--fullscreen", "-fs"].includes(args[0])) {
-    if (process.argv.length !== 2) console.log(e.ERASE.CLEAR_SCREEN)
+  if (
+    args.length === 0 ||
+    [
+      "--fullscreen",
+      "-fs",
+      "--force-weak-password",
+      "-fwp",
+      "--session-ttl",
+      "-st",
+    ].includes(args[0])
+  ) {
+    let fullscreen = false,
+      weak = false,
+      ttl = _SESSION_TTL
+    for (let i = 0; i < args.length; i++) {
+      if (["--fullscreen", "-fs"].includes(args[i])) fullscreen = true
+      else if (["--force-weak-password", "-fwp"].includes(args[i])) weak = true
+      else if (["--session-ttl", "-st"].includes(args[i])) {
+        ttl = parseInt(args[++i])
+        if (Number.isNaN(ttl) || ttl < 0 || ttl * 60000 > _MAX_TIMEOUT) {
+          console.log(WARN("Invalid session timeout."))
+          return
+        }
+      } else {
+        console.log(WARN("Invalid argument."))
+        return
+      }
+    }
+    if (fullscreen) console.log(e.ERASE.CLEAR_SCREEN)
     if (!fs.existsSync(__dirname + "/../config.json"))
-      fs.writeFileSync(
+      atomic.writeFileSync(
         __dirname + "/../config.json",
         '{"selected": "default","databases": ["default"]}'
       )
@@ -1860,7 +2199,7 @@ async function newPassword() {
     LOGO()
     console.log("")
     console.log(`\n${OK(`Database: [ ${_NAME} ]`)}\n`)
-    main()
+    main(weak, ttl)
   } else if (args[0] === "new") {
     if (args.length > 1) {
       console.log(WARN(`Expected 0 arg(s), received ${args.length - 1}`))
@@ -1871,7 +2210,10 @@ async function newPassword() {
     if (is(newName, _BASENAME) && newName.length !== 0) {
       if (!config.databases.includes(newName)) {
         config.databases.push(newName)
-        fs.writeFileSync(__dirname + "/../config.json", JSON.stringify(config))
+        atomic.writeFileSync(
+          __dirname + "/../config.json",
+          JSON.stringify(config)
+        )
         console.log(OK("Added new database."))
       } else {
         console.log(WARN("Database already exists."))
@@ -1900,7 +2242,10 @@ async function newPassword() {
     let config = getDatabases()
     if (config.databases.includes(args[1])) {
       config.selected = args[1]
-      fs.writeFileSync(__dirname + "/../config.json", JSON.stringify(config))
+      atomic.writeFileSync(
+        __dirname + "/../config.json",
+        JSON.stringify(config)
+      )
       console.log(OK(`Switched to ${args[1]} database.`))
     } else {
       console.log(WARN("Database not found."))
@@ -1922,12 +2267,14 @@ async function newPassword() {
         ) {
           if (fs.existsSync(__dirname + "/../databases/" + args[1] + ".json"))
             fs.unlinkSync(__dirname + "/../databases/" + args[1] + ".json")
+          if (fs.existsSync(__dirname + "/../databases/" + args[1] + ".lock"))
+            fs.unlinkSync(__dirname + "/../databases/" + args[1] + ".lock")
           config.databases.splice(config.databases.indexOf(args[1]), 1)
           if (config.selected === args[1]) {
             config.selected = config.databases[0]
           }
           console.log(OK(`Deleted ${args[1]} database.`))
-          fs.writeFileSync(
+          atomic.writeFileSync(
             __dirname + "/../config.json",
             JSON.stringify(config)
           )
@@ -1952,9 +2299,17 @@ async function newPassword() {
             __dirname + `/../databases/${args[1]}.json`,
             __dirname + `/../databases/${newDBName}.json`
           )
+        if (fs.existsSync(__dirname + "/../databases/" + args[1] + ".lock"))
+          fs.renameSync(
+            __dirname + `/../databases/${args[1]}.lock`,
+            __dirname + `/../databases/${newDBName}.lock`
+          )
         config.databases[config.databases.indexOf(args[1])] = newDBName
         console.log(OK(`Renamed ${args[1]} to ${newDBName}.`))
-        fs.writeFileSync(__dirname + "/../config.json", JSON.stringify(config))
+        atomic.writeFileSync(
+          __dirname + "/../config.json",
+          JSON.stringify(config)
+        )
       } else {
         console.log(
           WARN(
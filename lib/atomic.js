@@ -0,0 +1,44 @@
+/*
+ * krypt
+ * https://github.com/raklaptudirm/Krypt
+ *
+ * Copyright (c) 2021 Rak Laptudirm
+ * Licensed under the MIT license.
+ */
+
+const fs = require("fs")
+const path = require("path")
+
+module.exports = {
+  writeFileSync: (file, data) => {
+    const temp = file + ".tmp"
+    const mode = fs.existsSync(file) && fs.statSync(file).mode & 0o777
+    let fd
+    // A temp file left by a killed process would keep its old mode.
+    if (fs.existsSync(temp)) fs.unlinkSync(temp)
+    try {
+      fd = fs.openSync(temp, "wx", mode || 0o666)
+      fs.writeFileSync(fd, data)
+      // Keep the mode of the file being replaced, even if umask clears bits.
+      if (mode) fs.fchmodSync(fd, mode)
+      fs.fsyncSync(fd)
+      fs.closeSync(fd)
+      fd = undefined
+      fs.renameSync(temp, file)
+    } catch (err) {
+      if (fd !== undefined) fs.closeSync(fd)
+      if (fs.existsSync(temp)) fs.unlinkSync(temp)
+      throw err
+    }
+
+    // Directories can't be opened for syncing on Windows.
+    if (process.platform !== "win32") {
+      const dir = fs.openSync(path.dirname(file), "r")
+      try {
+        fs.fsyncSync(dir)
+      } finally {
+        fs.closeSync(dir)
+      }
+    }
+  },
+}
@@ -0,0 +1,29 @@
+/*
+ * krypt
+ * https://github.com/raklaptudirm/Krypt
+ *
+ * Copyright (c) 2021 Rak Laptudirm
+ * Licensed under the MIT license.
+ */
+
+/*
+ * Clipboard clearer
+ *
+ * Spawned detached by Krypt when a session ends
+ * with a clipboard clear still pending. Reads the
+ * copied text from stdin, waits for the remaining
+ * milliseconds given as the first arg, and clears
+ * the clipboard if it still holds that text.
+ */
+
+const clipboardy = require("clipboardy")
+
+let text = ""
+process.stdin.on("data", chunk => (text += chunk))
+process.stdin.on("end", () => {
+  setTimeout(() => {
+    try {
+      if (clipboardy.readSync() === text) clipboardy.writeSync("")
+    } catch {}
+  }, parseInt(process.argv[2]))
+})